@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ready flips to 1 once the config is loaded, every configured sink is
+// built and the queue backend is writable. /readyz reflects it.
+var ready int32
+
+func markReady() { atomic.StoreInt32(&ready, 1) }
+func isReady() bool { return atomic.LoadInt32(&ready) == 1 }
+
+// newAdminMux builds the mux serving /metrics, /healthz and /readyz. It can
+// be registered on the main listener or served on its own admin_addr.
+func newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		// Liveness: if the process can run this handler, it's alive.
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !isReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
@@ -24,18 +24,20 @@ package main
 //
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/tubemogul/catchpoint_api_sdk_go/alertAPI"
+	"github.com/grem11n/catchpoint_pushapi_client_go/plugin"
+	_ "github.com/grem11n/catchpoint_pushapi_client_go/plugins/catchpointalerts"
+	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
-	"strings"
-	"sync"
+	"syscall"
 	"time"
 )
 
@@ -53,189 +55,264 @@ var (
 )
 
 var config = new(Configuration)
-var cache []string
-
-// checkIpFiltering sends an empty response if an IP filtering is defined and
-// the IP is out of this filter.
-func checkIpFiltering(clientIP *string) bool {
-	if len(config.AuthIPs) > 0 {
-		client_ip := strings.Split(*clientIP, ":")[0]
-		for _, autorized_ip := range strings.Split(config.AuthIPs, ",") {
-			if client_ip == autorized_ip {
-				logInfo(fmt.Sprintf("Accepted IP: %s", client_ip))
-				return true
-			}
-		}
-
-		logInfo(fmt.Sprintf("Refused IP: %s", *clientIP))
-		return false
-	}
-	return true
-}
-
-// verifyRequestContent checks if the content of the request is empty. If yes,
-// returns an HTTP error 400.
-func verifyRequestContent(w *http.ResponseWriter, req *http.Request) bool {
-
-	logInfo(fmt.Sprintf("Length of the query: %d", req.ContentLength))
-
-	if req.ContentLength == 0 && req.Method != "GET" {
-		http.Error(*w, http.StatusText(400), 400)
-		return false
-	}
-	return true
-}
+var queue Queue
+var dispatcher *Dispatcher
 
-// The handler that will redirect to the correct plugin
-func genericHandler(w http.ResponseWriter, r *http.Request) {
+// makeReceiverHandler builds the handler for a single configured receiver
+// endpoint, bound to its own plugin. It replaces the old genericHandler,
+// which funneled every path through one function and then looped over
+// config.Endpoints to find the matching plugin on every request.
+func makeReceiverHandler(endpoint EndpointConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := loggerFromContext(ctx).With().Str("plugin", endpoint.PluginName).Logger()
 
-	logInfo(fmt.Sprintf("Receiving a new query from %s on %s", r.RemoteAddr, r.URL.Path))
-
-	// Doing nothing if the request is not from an authorized IP
-	if !checkIpFiltering(&(r.RemoteAddr)) {
-		return
-	}
+		if r.ContentLength == 0 {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
 
-	// Doing nothing if the POST request is empty
-	if !verifyRequestContent(&w, r) {
-		return
-	}
+		body, readErr := ioutil.ReadAll(r.Body)
+		handleErrorHttp(&logger, &readErr, &w)
+		if readErr != nil {
+			return
+		}
 
-	body, readErr := ioutil.ReadAll(r.Body)
-	handleErrorHttp(&readErr, &w)
-	if readErr != nil {
-		return
-	}
+		if *dumpRequestsDir != "" {
+			fName := fmt.Sprintf("%d_%d.txt", time.Now().UnixNano(), os.Getpid())
+			if err := ioutil.WriteFile(filepath.Join(*dumpRequestsDir, fName), body, 0644); err != nil {
+				logger.Error().Err(err).Str("file", fName).Msg("failed to dump request body")
+			}
+		}
 
-	if len(*dumpRequestsDir) >= 0 {
-		fName := fmt.Sprintf("%d_%d.txt", time.Now().UnixNano(), os.Getpid())
-		err := ioutil.WriteFile(filepath.Join(*dumpRequestsDir, fName), body, 0644)
-		logError(&err)
-	}
+		p, ok := plugin.Lookup(endpoint.PluginName)
+		if !ok {
+			logger.Error().Msg("unsupported plugin name")
+			http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+			return
+		}
 
-	var (
-		rc  uint8
-		svc *string
-		msg *[]string
-		err error
-	)
+		rc, svc, msg, err := p.RequestHandler(body)
+		handleErrorHttp(&logger, &err, &w)
+		if err != nil {
+			return
+		}
 
-	var mutex = &sync.Mutex{}
-	for _, endpoint := range config.Endpoints {
-		if endpoint.URIPath == r.URL.Path {
-			// Once you have the right endpoint, you check for the right plugin
-			switch endpoint.PluginName {
-			default:
-				errCust := fmt.Errorf("Unsupported plugin name for %s", endpoint.PluginName)
-				logError(&errCust)
-				return
-			case "catchpoint_alerts":
-				plugin := new(alertsAPI.Alert)
-				rc, svc, msg, err = plugin.RequestHandler(&body)
+		logger.Info().
+			Uint8("criticity", rc).
+			Str("service", svc).
+			Strs("messages", msg).
+			Msg("request handled")
 
-				// If there's an error un the handle of the request, logging the error
-				// and exiting.
-				handleErrorHttp(&err, &w)
-				if err != nil {
-					return
-				}
+		// Fan each failure out to every enabled sink (NSCA, Sensu, webhook,
+		// Elasticsearch, Kafka, ...) concurrently. A failure on one sink
+		// never blocks delivery to the others.
+		now := time.Now()
+		observeAlertCriticality(rc)
+		// context.Background, not ctx: ctx is r.Context(), which net/http
+		// cancels as soon as this handler returns, and this Dispatch call is
+		// deliberately detached so it can keep retrying after that. The
+		// logger is carried over explicitly so retry/failure lines from the
+		// detached dispatch still carry this request's request_id.
+		dispatchCtx := withLogger(context.Background(), logger)
+		for _, failure := range msg {
+			go dispatcher.Dispatch(dispatchCtx, Event{
+				Criticality: rc,
+				Service:     svc,
+				Message:     failure,
+				Timestamp:   now,
+			})
+		}
 
-				logInfo(fmt.Sprintf("Detected criticity = %d", rc))
-				logInfo(fmt.Sprintf("Service = %s", *svc))
-				logInfo(fmt.Sprintf("Msg = %+v", *msg))
+		// Marshal each failure and push it onto the bounded queue, for the
+		// Sensu-style poller endpoints to drain.
+		for _, failure := range msg {
+			raw := Sensu{
+				Status: rc,
+				Name:   svc,
+				Output: failure,
 			}
-
-			// Sending NSCA messages if enabled
-			if config.NSCA.Enabled {
-				// We send an nsca alert for each failures in the test to have a better
-				// report of the frequency of the failures
-				for _, failure := range *msg {
-					err := sendNscaMessage(&rc, svc, &failure)
-					handleErrorHttp(&err, &w)
-				}
+			res, err := json.Marshal(raw)
+			if err != nil {
+				handleErrorHttp(&logger, &err, &w)
+				continue
 			}
-			// Sending check results to channel
-			// And put in into the cache
-			channel := make(chan string)
-			go func() {
-				for _, failure := range *msg {
-					raw := Sensu{
-						Status: rc,
-						Name:   *svc,
-						Output: failure,
-					}
-					res, err := json.Marshal(raw)
-					if err != nil {
-						handleErrorHttp(&err, &w)
-					}
-					channel <- string(res)
-				}
-				close(channel)
-			}()
-			// Preserve cache with Mutex
-			mutex.Lock()
-			for msg := range channel {
-				cache = append(cache, msg)
+			if err := queue.Push(string(res)); err != nil {
+				logger.Warn().Err(err).Msg("failed to enqueue item")
 			}
-			mutex.Unlock()
-			logInfo(fmt.Sprintf("%d items been written to the cache", len(cache)))
-			break // break when you find the matching endpoint
 		}
+		logger.Debug().Int("queue_depth", queue.Len()).Msg("items written to the queue")
 	}
-	for _, sender := range config.Sender {
-		if sender.URIPath == r.URL.Path {
-			mutex.Lock()
-			for _, v := range cache {
-				fmt.Fprintf(w, "%q", v)
-			}
-			logInfo(fmt.Sprintf("%d items been read from the cache", len(cache)))
-			// Discard cache after read
-			cache = nil
-			mutex.Unlock()
+}
+
+// senderHandler drains the queue for a Sensu-style poller.
+func senderHandler(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+
+	drained := 0
+	for {
+		v, ok := queue.Pop()
+		if !ok {
+			break
 		}
+		fmt.Fprintf(w, "%q", v)
+		drained++
 	}
+	logger.Debug().Int("items", drained).Msg("items read from the queue")
 }
 
 // Main function
 func main() {
 	flag.Parse()
 
+	death := NewDeath()
+
 	// load plugins
 
 	// Loading the configuration
-	logInfo("Loading config")
+	baseLogger.Info().Msg("loading config")
 	err := config.loadConfig(*configFile)
 	if err != nil {
-		log.Fatal("Unable to laod configuration: %s", err)
+		baseLogger.Fatal().Err(err).Msg("unable to load configuration")
 	}
 
 	// Multithreading the http server
 	runtime.GOMAXPROCS(config.Procs)
 
+	var logOutput io.Writer
+	var logFile *os.File
 	if len(config.LogFile) > 0 {
-		logInfo(fmt.Sprintf("Setting the log output to %s", config.LogFile))
-		f, err := os.OpenFile(config.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-		logError(&err)
-		defer f.Close()
-		log.SetOutput(f)
+		logFile, err = os.OpenFile(config.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			baseLogger.Fatal().Err(err).Str("log_file", config.LogFile).Msg("unable to open log file")
+		}
+		logOutput = logFile
+	}
+	initLogger(config.Logging, logOutput)
+
+	baseLogger.Info().Str("log_file", config.LogFile).Msg("logger initialized")
+
+	sinks, err := buildSinks(config.Sinks)
+	if err != nil {
+		baseLogger.Fatal().Err(err).Msg("unable to build sinks")
+	}
+	workers, retries := config.SinkWorkers, config.SinkRetries
+	if workers <= 0 {
+		workers = 8
+	}
+	if retries <= 0 {
+		retries = 2
 	}
+	dispatcher = NewDispatcher(sinks, workers, retries)
+	baseLogger.Info().Int("sinks", len(sinks)).Msg("sinks initialized")
+
+	queue, err = newQueue(config.Queue)
+	if err != nil {
+		baseLogger.Fatal().Err(err).Msg("unable to build queue")
+	}
+	baseLogger.Info().Str("backend", config.Queue.Backend).Msg("queue initialized")
+	registerQueueMetrics(queue)
+
+	markReady()
 
-	// Default route. We use it to handle every request. The filtering out is done
-	// in the handler
-	// Creating channel
-	http.HandleFunc("/", genericHandler)
+	adminMux := newAdminMux()
+	var adminServer *http.Server
+	if config.AdminAddr != "" {
+		adminServer = &http.Server{Addr: config.AdminAddr, Handler: adminMux}
+		go func() {
+			baseLogger.Info().Str("addr", config.AdminAddr).Msg("starting admin server")
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				baseLogger.Error().Err(err).Msg("admin server exited")
+			}
+		}()
+	}
 
-	// Debug
-	//	val := <-c // read from channel
-	//	test, _ := json.Marshal(val)
-	//	logInfo(fmt.Sprintf(string(test)))
+	// Every configured endpoint and sender gets its own handler, method
+	// restriction and middleware chain.
+	mainMux, err := buildMainMux()
+	if err != nil {
+		baseLogger.Fatal().Err(err).Msg("unable to build http routes")
+	}
+	if config.AdminAddr == "" {
+		mainMux.Handle("/metrics", adminMux)
+		mainMux.Handle("/healthz", adminMux)
+		mainMux.Handle("/readyz", adminMux)
+	}
 
-	logInfo(fmt.Sprintf("Starting web server listening on %s:%d", config.IP, config.Port))
 	s := &http.Server{
 		Addr:           fmt.Sprintf("%s:%d", config.IP, config.Port),
+		Handler:        mainMux,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
-	log.Fatal(s.ListenAndServe())
+	go func() {
+		baseLogger.Info().Str("ip", config.IP).Int("port", config.Port).Msg("starting web server")
+		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			baseLogger.Fatal().Err(err).Msg("web server exited unexpectedly")
+		}
+	}()
+
+	// Shutdown order matters: stop taking new requests first, then drain
+	// what's already in flight, then persist the queue, then close the
+	// admin server, and finally the log file so every subsystem above can
+	// still log its own shutdown.
+	death.Register("http server", func(ctx context.Context) error { return s.Shutdown(ctx) })
+	death.Register("dispatcher", func(ctx context.Context) error {
+		if err := dispatcher.Drain(ctx); err != nil {
+			baseLogger.Warn().Err(err).Msg("dispatcher did not drain before deadline")
+		}
+		return dispatcher.Close()
+	})
+	death.Register("queue", func(ctx context.Context) error {
+		if err := queue.Flush(); err != nil {
+			return err
+		}
+		return queue.Close()
+	})
+	if adminServer != nil {
+		death.Register("admin server", func(ctx context.Context) error { return adminServer.Shutdown(ctx) })
+	}
+	if logFile != nil {
+		death.Register("log file", func(ctx context.Context) error { return logFile.Close() })
+	}
+
+	waitForShutdown(death)
+}
+
+// waitForShutdown blocks until SIGINT or SIGTERM is received, then runs the
+// shutdown sequence registered on death with a bounded grace period.
+//
+// SIGHUP re-reads the config file into the shared *config, but only
+// ShutdownGracePeriodSeconds (read fresh below, after the signal loop) is
+// actually picked back up by anything at runtime. Routes, middleware, sink
+// definitions, the queue backend and the log level are all baked into
+// closures or globals built once at startup; changing them in the config
+// file requires a restart, not a SIGHUP.
+func waitForShutdown(death *Death) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			baseLogger.Info().Msg("SIGHUP received, reloading shutdown grace period from config")
+			if err := config.loadConfig(*configFile); err != nil {
+				baseLogger.Error().Err(err).Msg("failed to reload configuration")
+			}
+			continue
+		}
+
+		baseLogger.Info().Str("signal", sig.String()).Msg("shutting down")
+		break
+	}
+
+	grace := time.Duration(config.ShutdownGracePeriodSeconds) * time.Second
+	if grace <= 0 {
+		grace = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	death.CloseAll(ctx)
+	baseLogger.Info().Msg("shutdown complete")
 }
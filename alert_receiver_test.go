@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testAlertXML = `<?xml version="1.0" encoding="UTF-8"?>
+<TestDetail>
+  <TestName>Homepage availability</TestName>
+  <TestType>Web</TestType>
+  <ChartStatus>2</ChartStatus>
+  <Timestamp>2026-07-27T10:00:00Z</Timestamp>
+  <FailureReasons>
+    <FailureReason>Connection timed out after 30000ms</FailureReason>
+  </FailureReasons>
+</TestDetail>
+`
+
+// TestMakeReceiverHandlerDumpRequestsDir guards against the
+// len(*dumpRequestsDir) >= 0 bug, which always evaluated true and dumped
+// every request's body regardless of whether --dump-requests-dir was set.
+func TestMakeReceiverHandlerDumpRequestsDir(t *testing.T) {
+	queue = newMemoryQueue(10, OverflowDropOldest)
+	dispatcher = NewDispatcher(nil, 1, 0)
+	t.Cleanup(func() { *dumpRequestsDir = "" })
+
+	endpoint := EndpointConfig{URIPath: "/catchpoint/alerts", PluginName: "catchpoint_alerts"}
+	handler := makeReceiverHandler(endpoint)
+
+	newRequest := func() {
+		req := httptest.NewRequest("POST", "/catchpoint/alerts", strings.NewReader(testAlertXML))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+	}
+	countEntries := func(dir string) int {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir(%s): %s", dir, err)
+		}
+		return len(entries)
+	}
+
+	dir := t.TempDir()
+
+	*dumpRequestsDir = ""
+	newRequest()
+	if got := countEntries(dir); got != 0 {
+		t.Fatalf("dump dir has %d entries with dumping disabled, want 0", got)
+	}
+
+	*dumpRequestsDir = dir
+	newRequest()
+	if got := countEntries(dir); got != 1 {
+		t.Fatalf("dump dir has %d entries with dumping enabled, want 1", got)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// Configuration holds every setting loaded from the JSON file pointed to by
+// the --config flag.
+type Configuration struct {
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	Procs   int    `json:"procs"`
+	LogFile string `json:"log_file"`
+
+	Endpoints []EndpointConfig `json:"endpoints"`
+	Sender    []SenderConfig   `json:"sender"`
+
+	// Sinks lists every output sink (NSCA, Sensu, webhook, Elasticsearch,
+	// Kafka, ...) that a received event should be fanned out to.
+	Sinks []SinkConfig `json:"sinks"`
+	// SinkWorkers bounds how many Sink.Send calls may run concurrently
+	// across all events. Defaults to 8.
+	SinkWorkers int `json:"sink_workers"`
+	// SinkRetries is how many extra attempts are made on a sink before its
+	// delivery for a given event is given up on. Defaults to 2.
+	SinkRetries int `json:"sink_retries"`
+
+	// Queue configures the bounded queue (durable with the disk backend)
+	// that sits between a received event and the sender endpoints that
+	// drain it.
+	Queue QueueConfig `json:"queue"`
+
+	// AdminAddr, if set, serves /metrics, /healthz and /readyz on their own
+	// listener instead of the main one.
+	AdminAddr string `json:"admin_addr"`
+
+	// ShutdownGracePeriodSeconds bounds how long a SIGINT/SIGTERM is given
+	// to drain in-flight requests and sink sends before the process exits
+	// anyway. Defaults to 30.
+	ShutdownGracePeriodSeconds int `json:"shutdown_grace_period_seconds"`
+
+	// Logging controls the structured logger's verbosity and output format.
+	Logging LoggingConfig `json:"logging"`
+}
+
+// EndpointConfig binds an incoming URI path to the plugin that should handle
+// it, plus the middleware chain guarding that path.
+type EndpointConfig struct {
+	URIPath    string `json:"uri_path"`
+	PluginName string `json:"plugin_name"`
+
+	MiddlewareConfig
+}
+
+// SenderConfig declares a URI path that a Sensu-style poller can GET to
+// drain the queue, plus the middleware chain guarding that path.
+type SenderConfig struct {
+	URIPath string `json:"uri_path"`
+
+	MiddlewareConfig
+}
+
+// MiddlewareConfig holds the settings shared by every endpoint's middleware
+// chain: IP allow-listing, HMAC signature verification, basic auth and a
+// request size cap.
+type MiddlewareConfig struct {
+	// AllowCIDRs restricts the endpoint to these CIDR blocks. Empty means
+	// no restriction.
+	AllowCIDRs []string `json:"allow_cidrs"`
+	// HMACSecret, if set, requires an `X-Signature` header carrying the
+	// HMAC-SHA256 of the request body keyed with this secret.
+	HMACSecret string `json:"hmac_secret"`
+	// BasicAuthUser/BasicAuthPassword, if BasicAuthUser is set, require
+	// matching HTTP basic auth credentials.
+	BasicAuthUser     string `json:"basic_auth_user"`
+	BasicAuthPassword string `json:"basic_auth_password"`
+	// MaxBodyBytes caps the request body size; 0 means no cap.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+}
+
+// LoggingConfig controls the structured logger's verbosity and output
+// format.
+type LoggingConfig struct {
+	// Level is one of "debug", "info", "warn", "error". Defaults to "info".
+	Level string `json:"level"`
+	// Format is either "json" or "console". Defaults to "json".
+	Format string `json:"format"`
+}
+
+// loadConfig reads and unmarshals the JSON configuration file at path into c.
+func (c *Configuration) loadConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
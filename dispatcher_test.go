@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink is a Sink whose Send behavior is scripted by failAttempts: it
+// fails the first failAttempts calls, then succeeds. It also records every
+// ctx it was called with, so tests can assert on cancellation.
+type fakeSink struct {
+	mu           sync.Mutex
+	failAttempts int
+	calls        int
+	ctxErrs      []error
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.ctxErrs = append(s.ctxErrs, ctx.Err())
+	if s.calls <= s.failAttempts {
+		return errors.New("simulated failure")
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error { return nil }
+
+func TestDispatcherRetriesUntilSuccess(t *testing.T) {
+	sink := &fakeSink{failAttempts: 2}
+	d := NewDispatcher([]Sink{sink}, 4, 2)
+
+	d.Dispatch(context.Background(), Event{Service: "svc", Message: "boom"})
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.calls != 3 {
+		t.Fatalf("Send called %d times, want 3 (2 failures + 1 success)", sink.calls)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxRetries(t *testing.T) {
+	sink := &fakeSink{failAttempts: 100}
+	d := NewDispatcher([]Sink{sink}, 4, 2)
+
+	d.Dispatch(context.Background(), Event{Service: "svc", Message: "boom"})
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if want := 3; sink.calls != want { // maxRetries(2) + the initial attempt
+		t.Fatalf("Send called %d times, want %d", sink.calls, want)
+	}
+}
+
+// TestDispatcherHonorsCallerContext guards against reusing a short-lived
+// request context for a detached Dispatch call: if the context passed in is
+// already canceled, every sink sees that cancellation on its very first
+// attempt, which is exactly what broke when alert_receiver.go handed
+// Dispatch the inbound request's context instead of a detached one.
+func TestDispatcherHonorsCallerContext(t *testing.T) {
+	sink := &fakeSink{}
+	d := NewDispatcher([]Sink{sink}, 4, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	d.Dispatch(ctx, Event{Service: "svc", Message: "boom"})
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.ctxErrs) != 1 || sink.ctxErrs[0] != context.Canceled {
+		t.Fatalf("ctxErrs = %v, want [context.Canceled]", sink.ctxErrs)
+	}
+}
+
+// Dispatch returns only once every sink is done, so by the time Drain runs
+// here nothing is in flight and it must return nil well before the deadline.
+func TestDispatcherDrainReturnsOnceIdle(t *testing.T) {
+	sink := &fakeSink{}
+	d := NewDispatcher([]Sink{sink}, 4, 0)
+
+	d.Dispatch(context.Background(), Event{Service: "svc", Message: "boom"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ctxKey int
+
+// loggerCtxKey is the key under which the request-scoped logger is stashed
+// in a context.Context.
+const loggerCtxKey ctxKey = 0
+
+// baseLogger is the root logger. It is replaced by initLogger once the
+// configuration has been loaded; until then it logs at info level to
+// stderr so early startup errors are never silently dropped.
+var baseLogger = zerolog.New(os.Stderr).Level(zerolog.InfoLevel).With().Timestamp().Logger()
+
+// initLogger rebuilds baseLogger from the Logging section of the config. It
+// must be called once in main(), right after the config is loaded, and
+// before any other subsystem is started.
+func initLogger(cfg LoggingConfig, out io.Writer) {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	// An empty level parses to zerolog.NoLevel without error, which would
+	// silence every log line.
+	if err != nil || level == zerolog.NoLevel {
+		level = zerolog.InfoLevel
+	}
+
+	if out == nil {
+		out = os.Stderr
+	}
+	if cfg.Format == "console" {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	}
+
+	baseLogger = zerolog.New(out).Level(level).With().Timestamp().Logger()
+}
+
+// loggerFromContext returns the request-scoped logger carried by ctx,
+// falling back to baseLogger if none was attached.
+func loggerFromContext(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(loggerCtxKey).(*zerolog.Logger); ok {
+		return l
+	}
+	return &baseLogger
+}
+
+// withLogger returns a copy of ctx carrying l as its request-scoped logger.
+func withLogger(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey, &l)
+}
+
+// statusWriter wraps http.ResponseWriter to capture the status code written
+// by the wrapped handler, so it can be logged once the request completes.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// loggingMiddleware assigns each request a request-id and attaches a logger
+// carrying the remote address, URI and request-id to its context, then logs
+// the request's start and end, with latency and status code.
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqLogger := baseLogger.With().
+			Str("request_id", newRequestID()).
+			Str("remote_addr", r.RemoteAddr).
+			Str("uri", r.URL.Path).
+			Logger()
+
+		ctx := withLogger(r.Context(), reqLogger)
+		r = r.WithContext(ctx)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		reqLogger.Debug().Msg("request started")
+		next.ServeHTTP(sw, r)
+		latency := time.Since(start)
+		observeRequest(r.URL.Path, sw.status, latency)
+		reqLogger.Info().
+			Int("status", sw.status).
+			Dur("latency", latency).
+			Msg("request finished")
+	})
+}
+
+// newRequestID returns a short, unique identifier used to correlate every
+// log line produced while handling a single request.
+func newRequestID() string {
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+// handleErrorHttp logs *err through logger, if non-nil, and writes a 500 to
+// *w. It is a no-op when *err is nil, so call sites can invoke it
+// unconditionally right after an operation that returns an error.
+func handleErrorHttp(logger *zerolog.Logger, err *error, w *http.ResponseWriter) {
+	if err == nil || *err == nil {
+		return
+	}
+	logger.Error().Err(*err).Msg("request failed")
+	http.Error(*w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
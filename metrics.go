@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// requestsTotal counts every HTTP request that passes through
+	// loggingMiddleware, by endpoint path and response status.
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pushapi_requests_total",
+		Help: "Total number of HTTP requests received, by endpoint and status.",
+	}, []string{"endpoint", "status"})
+
+	// requestDuration tracks end-to-end request latency, by endpoint.
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pushapi_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// sinkSendTotal counts every Sink.Send call, by sink name and outcome
+	// ("success" or "failure").
+	sinkSendTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pushapi_sink_send_total",
+		Help: "Total number of sink send attempts, by sink and result.",
+	}, []string{"sink", "result"})
+
+	// sinkSendDuration tracks how long each sink takes to accept an event.
+	sinkSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pushapi_sink_send_duration_seconds",
+		Help:    "Sink send latency in seconds, by sink.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"sink"})
+
+	// alertCriticalityTotal counts received alerts (one per successfully
+	// parsed push request) by Catchpoint criticality level.
+	alertCriticalityTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pushapi_alert_criticality_total",
+		Help: "Total number of alerts received, by criticality level.",
+	}, []string{"criticality"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		requestsTotal,
+		requestDuration,
+		sinkSendTotal,
+		sinkSendDuration,
+		alertCriticalityTotal,
+	)
+}
+
+// registerQueueMetrics wires queue depth, oldest-item age and drop count as
+// gauges/counters that are read from q at scrape time, rather than pushed on
+// every change.
+func registerQueueMetrics(q Queue) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pushapi_queue_depth",
+		Help: "Number of items currently sitting in the queue.",
+	}, func() float64 { return float64(q.Len()) }))
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pushapi_queue_oldest_item_age_seconds",
+		Help: "Age of the oldest item in the queue, in seconds.",
+	}, func() float64 { return q.OldestAge().Seconds() }))
+
+	prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "pushapi_queue_drops_total",
+		Help: "Total number of items dropped because the queue was full.",
+	}, func() float64 { return float64(q.Drops()) }))
+}
+
+// observeRequest records requestsTotal/requestDuration for one finished
+// request.
+func observeRequest(endpoint string, status int, duration time.Duration) {
+	requestsTotal.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// observeSinkSend records sinkSendTotal/sinkSendDuration for one Sink.Send
+// attempt.
+func observeSinkSend(sink string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	sinkSendTotal.WithLabelValues(sink, result).Inc()
+	sinkSendDuration.WithLabelValues(sink).Observe(duration.Seconds())
+}
+
+// observeAlertCriticality records the criticality of one received alert.
+func observeAlertCriticality(criticality uint8) {
+	alertCriticalityTotal.WithLabelValues(strconv.Itoa(int(criticality))).Inc()
+}
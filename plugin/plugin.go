@@ -0,0 +1,42 @@
+// Package plugin defines the interface every Catchpoint Push API receiver
+// plugin implements, plus a global registry plugins add themselves to from
+// their own package's init(). This replaces the old hard-coded
+// `switch endpoint.PluginName` in genericHandler, which had exactly one
+// case and a default error, making it impossible to add a new plugin
+// without editing that file.
+package plugin
+
+// Plugin turns a raw push-API request body into a criticality, service
+// name and list of failure messages.
+type Plugin interface {
+	// Name identifies the plugin; it's what config.Endpoints[i].PluginName
+	// is matched against.
+	Name() string
+	// RequestHandler parses body and returns the criticality, service name
+	// and failure messages it contains.
+	RequestHandler(body []byte) (rc uint8, service string, messages []string, err error)
+}
+
+var registry = map[string]Plugin{}
+
+// Register adds p to the registry, keyed by p.Name(). It is meant to be
+// called from a plugin package's init().
+func Register(p Plugin) {
+	registry[p.Name()] = p
+}
+
+// Lookup returns the plugin registered under name, if any.
+func Lookup(name string) (Plugin, bool) {
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Registered returns the names of every currently registered plugin, handy
+// for tests and startup logging.
+func Registered() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,27 @@
+// Package catchpointalerts adapts the existing alertsAPI.Alert handler
+// (the Push API's "test failed" alert format) to the plugin.Plugin
+// interface, and registers itself under the "catchpoint_alerts" name that
+// receiver.cfg.json's endpoints already use.
+package catchpointalerts
+
+import (
+	"github.com/grem11n/catchpoint_pushapi_client_go/plugin"
+	"github.com/tubemogul/catchpoint_api_sdk_go/alertAPI"
+)
+
+func init() {
+	plugin.Register(&catchpointAlerts{})
+}
+
+type catchpointAlerts struct{}
+
+func (p *catchpointAlerts) Name() string { return "catchpoint_alerts" }
+
+func (p *catchpointAlerts) RequestHandler(body []byte) (uint8, string, []string, error) {
+	alert := new(alertsAPI.Alert)
+	rc, svc, msg, err := alert.RequestHandler(&body)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	return rc, *svc, *msg, nil
+}
@@ -0,0 +1,48 @@
+// Package plugins is test-only glue: it blank-imports every plugin package
+// so they register themselves, then feeds each one the recorded fixtures
+// under testdata/ to check that it parses them without error. A registered
+// plugin with no entry in fixtures is skipped, not failed.
+package plugins
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grem11n/catchpoint_pushapi_client_go/plugin"
+	_ "github.com/grem11n/catchpoint_pushapi_client_go/plugins/catchpointalerts"
+)
+
+// fixtures maps a registered plugin name to the recorded request bodies it
+// should be able to parse without error.
+var fixtures = map[string][]string{
+	"catchpoint_alerts": {"catchpoint_alerts_alert.xml"},
+}
+
+func TestRegisteredPluginsParseFixtures(t *testing.T) {
+	for _, name := range plugin.Registered() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			p, ok := plugin.Lookup(name)
+			if !ok {
+				t.Fatalf("plugin %q not found in registry", name)
+			}
+
+			files, ok := fixtures[name]
+			if !ok {
+				t.Skipf("no fixtures recorded for plugin %q", name)
+			}
+
+			for _, file := range files {
+				body, err := ioutil.ReadFile(filepath.Join("testdata", file))
+				if err != nil {
+					t.Fatalf("reading fixture %s: %s", file, err)
+				}
+
+				if _, _, _, err := p.RequestHandler(body); err != nil {
+					t.Errorf("RequestHandler(%s) returned error: %s", file, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy controls what a bounded Queue does once it is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest queued item to make room for
+	// the new one.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowRejectNew refuses the new item, leaving the queue untouched.
+	OverflowRejectNew OverflowPolicy = "reject-new"
+)
+
+// Queue is a bounded buffer of not-yet-delivered payloads sitting between
+// the receiver handlers and the senders that drain it; the disk backend
+// keeps it across restarts, the memory backend does not. It replaces the
+// old unbounded `cache []string` slice, which lost everything on restart
+// and could grow without limit under load.
+type Queue interface {
+	// Push enqueues payload, applying the configured overflow policy if the
+	// queue is already at its maximum size.
+	Push(payload string) error
+	// Pop removes and returns the oldest payload, if any.
+	Pop() (string, bool)
+	// Len returns the number of items currently queued.
+	Len() int
+	// OldestAge returns how long the oldest queued item has been waiting,
+	// or zero if the queue is empty.
+	OldestAge() time.Duration
+	// Drops returns the number of items discarded because the queue was
+	// full, since the queue was created.
+	Drops() uint64
+	// Flush persists any in-memory state to durable storage. The disk queue
+	// already commits every Push, and the memory queue has no durable
+	// storage, so for both it is a no-op.
+	Flush() error
+	// Close flushes and releases any resources (open files, ...) held by
+	// the queue.
+	Close() error
+}
+
+// QueueConfig is the `queue` section of the config file.
+type QueueConfig struct {
+	// Backend is either "memory" or "disk". Defaults to "memory".
+	Backend string `json:"backend"`
+	// MaxSize bounds how many items the queue holds at once. Defaults to
+	// 10000.
+	MaxSize int `json:"max_size"`
+	// OverflowPolicy is "drop-oldest" or "reject-new". Defaults to
+	// "drop-oldest".
+	OverflowPolicy OverflowPolicy `json:"overflow_policy"`
+	// Path is the file (or directory, for segmented backends) the disk
+	// backend persists its write-ahead log to.
+	Path string `json:"path"`
+}
+
+// newQueue constructs the Queue backend described by cfg.
+func newQueue(cfg QueueConfig) (Queue, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10000
+	}
+	if cfg.OverflowPolicy == "" {
+		cfg.OverflowPolicy = OverflowDropOldest
+	}
+
+	switch cfg.Backend {
+	case "", "memory":
+		return newMemoryQueue(cfg.MaxSize, cfg.OverflowPolicy), nil
+	case "disk":
+		return newDiskQueue(cfg.Path, cfg.MaxSize, cfg.OverflowPolicy)
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.Backend)
+	}
+}
+
+// item pairs a payload with the time it was enqueued, so OldestAge can be
+// computed without re-parsing the payload.
+type item struct {
+	payload    string
+	enqueuedAt time.Time
+}
+
+// memoryQueue is a bounded ring buffer over a fixed-size backing array: head
+// and count track the live window, so draining and refilling it never
+// reallocates. It is fast but loses its contents on restart, so it's meant
+// for deployments that can tolerate dropping alerts across a redeploy in
+// exchange for simplicity.
+type memoryQueue struct {
+	mu      sync.Mutex
+	items   []item
+	head    int
+	count   int
+	maxSize int
+	policy  OverflowPolicy
+	drops   uint64
+}
+
+func newMemoryQueue(maxSize int, policy OverflowPolicy) *memoryQueue {
+	return &memoryQueue{
+		items:   make([]item, maxSize),
+		maxSize: maxSize,
+		policy:  policy,
+	}
+}
+
+func (q *memoryQueue) Push(payload string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.count >= q.maxSize {
+		switch q.policy {
+		case OverflowRejectNew:
+			q.drops++
+			return fmt.Errorf("queue full (%d items), rejecting new item", q.maxSize)
+		default: // OverflowDropOldest
+			q.head = (q.head + 1) % q.maxSize
+			q.count--
+			q.drops++
+		}
+	}
+
+	tail := (q.head + q.count) % q.maxSize
+	q.items[tail] = item{payload: payload, enqueuedAt: time.Now()}
+	q.count++
+	return nil
+}
+
+func (q *memoryQueue) Pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.count == 0 {
+		return "", false
+	}
+	next := q.items[q.head]
+	q.head = (q.head + 1) % q.maxSize
+	q.count--
+	return next.payload, true
+}
+
+func (q *memoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+func (q *memoryQueue) OldestAge() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.count == 0 {
+		return 0
+	}
+	return time.Since(q.items[q.head].enqueuedAt)
+}
+
+func (q *memoryQueue) Drops() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.drops
+}
+
+func (q *memoryQueue) Flush() error { return nil }
+func (q *memoryQueue) Close() error { return nil }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var queueBucket = []byte("queue")
+
+// diskRecord is what gets persisted for each queued item: the payload plus
+// the time it was enqueued, so OldestAge survives a restart too.
+type diskRecord struct {
+	Payload    string    `json:"payload"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// diskQueue is a write-ahead-log-backed Queue built on bbolt: every Push is
+// a fsync'd transaction, so queued alerts survive a process restart or
+// crash instead of evaporating like the old in-memory cache did.
+type diskQueue struct {
+	db      *bbolt.DB
+	maxSize int
+	policy  OverflowPolicy
+	drops   uint64
+}
+
+func newDiskQueue(path string, maxSize int, policy OverflowPolicy) (*diskQueue, error) {
+	if path == "" {
+		path = "queue.db"
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening queue file %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(queueBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing queue bucket: %w", err)
+	}
+
+	return &diskQueue{db: db, maxSize: maxSize, policy: policy}, nil
+}
+
+func (q *diskQueue) Push(payload string) error {
+	return q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+
+		if bucket.Stats().KeyN >= q.maxSize {
+			switch q.policy {
+			case OverflowRejectNew:
+				atomic.AddUint64(&q.drops, 1)
+				return fmt.Errorf("queue full (%d items), rejecting new item", q.maxSize)
+			default: // OverflowDropOldest
+				c := bucket.Cursor()
+				if k, _ := c.First(); k != nil {
+					if err := bucket.Delete(k); err != nil {
+						return err
+					}
+					atomic.AddUint64(&q.drops, 1)
+				}
+			}
+		}
+
+		record, err := json.Marshal(diskRecord{Payload: payload, EnqueuedAt: time.Now()})
+		if err != nil {
+			return fmt.Errorf("marshaling record: %w", err)
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(itob(seq), record)
+	})
+}
+
+func (q *diskQueue) Pop() (string, bool) {
+	var payload string
+	var found bool
+
+	q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		c := bucket.Cursor()
+		k, v := c.First()
+		if k == nil {
+			return nil
+		}
+
+		var record diskRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		payload = record.Payload
+		found = true
+		return bucket.Delete(k)
+	})
+
+	return payload, found
+}
+
+func (q *diskQueue) Len() int {
+	var n int
+	q.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(queueBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+func (q *diskQueue) OldestAge() time.Duration {
+	var age time.Duration
+	q.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(queueBucket).Cursor()
+		_, v := c.First()
+		if v == nil {
+			return nil
+		}
+		var record diskRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		age = time.Since(record.EnqueuedAt)
+		return nil
+	})
+	return age
+}
+
+func (q *diskQueue) Drops() uint64 {
+	return atomic.LoadUint64(&q.drops)
+}
+
+// Flush is a no-op: every Push is already committed (and fsync'd) to the
+// WAL as part of its own bbolt transaction.
+func (q *diskQueue) Flush() error { return nil }
+
+func (q *diskQueue) Close() error { return q.db.Close() }
+
+// itob encodes a bbolt sequence number as a big-endian key, so the bucket's
+// natural key order matches insertion (FIFO) order.
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
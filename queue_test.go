@@ -0,0 +1,109 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// queueFactories lets the overflow-policy tests below run against every
+// Queue backend without duplicating the assertions.
+var queueFactories = map[string]func(t *testing.T, maxSize int, policy OverflowPolicy) Queue{
+	"memory": func(t *testing.T, maxSize int, policy OverflowPolicy) Queue {
+		return newMemoryQueue(maxSize, policy)
+	},
+	"disk": func(t *testing.T, maxSize int, policy OverflowPolicy) Queue {
+		q, err := newDiskQueue(filepath.Join(t.TempDir(), "queue.db"), maxSize, policy)
+		if err != nil {
+			t.Fatalf("newDiskQueue: %s", err)
+		}
+		t.Cleanup(func() { q.Close() })
+		return q
+	},
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	for name, newQueue := range queueFactories {
+		t.Run(name, func(t *testing.T) {
+			q := newQueue(t, 3, OverflowDropOldest)
+
+			for _, payload := range []string{"a", "b", "c", "d"} {
+				if err := q.Push(payload); err != nil {
+					t.Fatalf("Push(%q): %s", payload, err)
+				}
+			}
+
+			if got, want := q.Len(), 3; got != want {
+				t.Fatalf("Len() = %d, want %d", got, want)
+			}
+			if got, want := q.Drops(), uint64(1); got != want {
+				t.Fatalf("Drops() = %d, want %d", got, want)
+			}
+
+			var got []string
+			for {
+				v, ok := q.Pop()
+				if !ok {
+					break
+				}
+				got = append(got, v)
+			}
+			want := []string{"b", "c", "d"}
+			if len(got) != len(want) {
+				t.Fatalf("drained %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("drained %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueRejectNew(t *testing.T) {
+	for name, newQueue := range queueFactories {
+		t.Run(name, func(t *testing.T) {
+			q := newQueue(t, 2, OverflowRejectNew)
+
+			if err := q.Push("a"); err != nil {
+				t.Fatalf("Push(a): %s", err)
+			}
+			if err := q.Push("b"); err != nil {
+				t.Fatalf("Push(b): %s", err)
+			}
+			if err := q.Push("c"); err == nil {
+				t.Fatal("Push(c) on a full queue: want error, got nil")
+			}
+
+			if got, want := q.Len(), 2; got != want {
+				t.Fatalf("Len() = %d, want %d", got, want)
+			}
+			if got, want := q.Drops(), uint64(1); got != want {
+				t.Fatalf("Drops() = %d, want %d", got, want)
+			}
+
+			v, ok := q.Pop()
+			if !ok || v != "a" {
+				t.Fatalf("Pop() = (%q, %v), want (\"a\", true)", v, ok)
+			}
+		})
+	}
+}
+
+// TestMemoryQueueSustainedLoadBoundsCapacity guards against the
+// shrink-then-append approximation: repeatedly pushing past capacity must
+// not grow the backing array beyond maxSize.
+func TestMemoryQueueSustainedLoadBoundsCapacity(t *testing.T) {
+	q := newMemoryQueue(4, OverflowDropOldest)
+	for i := 0; i < 1000; i++ {
+		if err := q.Push("x"); err != nil {
+			t.Fatalf("Push: %s", err)
+		}
+	}
+	if got, want := cap(q.items), 4; got != want {
+		t.Fatalf("cap(items) = %d, want %d", got, want)
+	}
+	if got, want := q.Len(), 4; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+}
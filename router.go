@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// Middleware wraps an http.Handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// chain composes middlewares so that chain(a, b, c)(h) behaves as
+// a(b(c(h))): the first middleware in the list runs outermost.
+func chain(middlewares ...Middleware) Middleware {
+	return func(final http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			final = middlewares[i](final)
+		}
+		return final
+	}
+}
+
+// remoteIP extracts the client IP from a request's RemoteAddr, handling
+// both the IPv4 ("1.2.3.4:1234") and IPv6 ("[::1]:1234") forms. The old
+// checkIpFiltering used strings.Split(addr, ":")[0], which mangled every
+// IPv6 address since the address itself contains colons.
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// methodMiddleware rejects any request whose method isn't allowed, with a
+// 405 and an Allow header.
+func methodMiddleware(allowed string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != allowed {
+				w.Header().Set("Allow", allowed)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ipAllowlistMiddleware rejects any request whose remote address doesn't
+// fall within one of cidrs. An empty (or unconfigured) list allows
+// everything through; a cidrs entry that fails to parse is a config error,
+// not "no restriction", so it's returned instead of silently dropped - a
+// typo'd allow_cidrs entry must fail startup, not fail the allowlist open.
+func ipAllowlistMiddleware(cidrs []string) (Middleware, error) {
+	var allowed []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("parsing allow_cidrs entry %q: %w", c, err)
+		}
+		allowed = append(allowed, n)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(allowed) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := net.ParseIP(remoteIP(r.RemoteAddr))
+			for _, n := range allowed {
+				if ip != nil && n.Contains(ip) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			loggerFromContext(r.Context()).Warn().Str("remote_addr", r.RemoteAddr).Msg("rejected by IP allowlist")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}, nil
+}
+
+// maxBodyBytesMiddleware caps the request body size, failing the read with
+// an error once limit bytes have been consumed. A limit of 0 disables the
+// cap.
+func maxBodyBytesMiddleware(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// basicAuthMiddleware requires HTTP basic auth matching username/password.
+// An empty username disables the check.
+func basicAuthMiddleware(username, password string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != username || pass != password {
+				w.Header().Set("WWW-Authenticate", `Basic realm="pushapi"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hmacMiddleware verifies that the `X-Signature` header carries a valid
+// hex-encoded HMAC-SHA256 of the request body, keyed with secret. An empty
+// secret disables the check. It buffers the body so the downstream handler
+// can still read it in full afterwards.
+func hmacMiddleware(secret string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if secret == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			expected := hex.EncodeToString(mac.Sum(nil))
+
+			if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Signature"))) {
+				loggerFromContext(r.Context()).Warn().Msg("rejected request with invalid HMAC signature")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// endpointChain builds the standard middleware chain for an endpoint's
+// MiddlewareConfig: IP allow-list, body size cap, basic auth, then HMAC
+// verification.
+func endpointChain(cfg MiddlewareConfig) (Middleware, error) {
+	ipAllowlist, err := ipAllowlistMiddleware(cfg.AllowCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("allow_cidrs: %w", err)
+	}
+	return chain(
+		ipAllowlist,
+		maxBodyBytesMiddleware(cfg.MaxBodyBytes),
+		basicAuthMiddleware(cfg.BasicAuthUser, cfg.BasicAuthPassword),
+		hmacMiddleware(cfg.HMACSecret),
+	), nil
+}
+
+// buildMainMux registers one handler per configured endpoint and sender,
+// each bound to its own plugin/method/middleware chain, replacing the old
+// single genericHandler that funneled every path through one function and
+// then looped over config.Endpoints/config.Sender to figure out what to do.
+func buildMainMux() (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+
+	for _, endpoint := range config.Endpoints {
+		ep := endpoint
+		mw, err := endpointChain(ep.MiddlewareConfig)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ep.URIPath, err)
+		}
+		handler := loggingMiddleware(
+			mw(
+				methodMiddleware(http.MethodPost)(
+					http.HandlerFunc(makeReceiverHandler(ep)),
+				),
+			),
+		)
+		mux.Handle(ep.URIPath, handler)
+	}
+
+	for _, sender := range config.Sender {
+		s := sender
+		mw, err := endpointChain(s.MiddlewareConfig)
+		if err != nil {
+			return nil, fmt.Errorf("sender %q: %w", s.URIPath, err)
+		}
+		handler := loggingMiddleware(
+			mw(
+				methodMiddleware(http.MethodGet)(
+					http.HandlerFunc(senderHandler),
+				),
+			),
+		)
+		mux.Handle(s.URIPath, handler)
+	}
+
+	return mux, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestRemoteIP(t *testing.T) {
+	cases := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"ipv4", "203.0.113.4:51820", "203.0.113.4"},
+		{"ipv6", "[2001:db8::1]:51820", "2001:db8::1"},
+		{"malformed, no port", "203.0.113.4", "203.0.113.4"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := remoteIP(tc.remoteAddr); got != tc.want {
+				t.Errorf("remoteIP(%q) = %q, want %q", tc.remoteAddr, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Death coordinates graceful shutdown: subsystems register a close function
+// with it as they start up, and CloseAll runs every one of them, in
+// registration order, once the process is asked to stop.
+type Death struct {
+	mu      sync.Mutex
+	closers []deathCloser
+}
+
+type deathCloser struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// NewDeath returns an empty Death coordinator.
+func NewDeath() *Death {
+	return &Death{}
+}
+
+// Register adds name's close function to the shutdown sequence. Subsystems
+// should register themselves as soon as they've successfully started.
+func (d *Death) Register(name string, close func(ctx context.Context) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closers = append(d.closers, deathCloser{name: name, close: close})
+}
+
+// CloseAll runs every registered close function in registration order,
+// logging and continuing past any individual failure so one stuck subsystem
+// doesn't prevent the others from shutting down cleanly.
+func (d *Death) CloseAll(ctx context.Context) {
+	d.mu.Lock()
+	closers := append([]deathCloser(nil), d.closers...)
+	d.mu.Unlock()
+
+	for _, c := range closers {
+		if err := c.close(ctx); err != nil {
+			baseLogger.Error().Err(err).Str("subsystem", c.name).Msg("error closing subsystem during shutdown")
+			continue
+		}
+		baseLogger.Info().Str("subsystem", c.name).Msg("subsystem closed")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// decodeOptions re-marshals a sink's raw `options` map and unmarshals it
+// into target, letting each sink declare its own strongly-typed options
+// struct instead of poking around in a map[string]interface{}.
+func decodeOptions(options map[string]interface{}, target interface{}) error {
+	raw, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// Event is the normalized form of a single check result produced by a
+// plugin's RequestHandler, ready to be fanned out to every enabled sink.
+type Event struct {
+	Criticality uint8
+	Service     string
+	Message     string
+	Timestamp   time.Time
+}
+
+// Sink is anything that can receive an Event: NSCA, a Sensu Agent, a
+// webhook, Elasticsearch, Kafka, ... Implementations must be safe for
+// concurrent use, since a single Event is dispatched to every sink at once.
+type Sink interface {
+	// Name identifies the sink in logs and metrics.
+	Name() string
+	// Send delivers event to the sink. It may be called concurrently.
+	Send(ctx context.Context, event Event) error
+	// Close releases any resources held by the sink (connections,
+	// producers, ...). It is called once, at shutdown.
+	Close() error
+}
+
+// SinkConfig describes one entry of the `sinks` array in the config file.
+// Options is kept as a generic map and decoded by the matching sink's
+// constructor, since each sink type needs a different shape.
+type SinkConfig struct {
+	Type    string                 `json:"type"`
+	Name    string                 `json:"name"`
+	Enabled bool                   `json:"enabled"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// Dispatcher fans an Event out to every configured sink concurrently,
+// bounding the number of in-flight sends and retrying each sink
+// independently so a slow or broken sink never blocks the others.
+type Dispatcher struct {
+	sinks      []Sink
+	maxRetries int
+	tokens     chan struct{}
+	inFlight   sync.WaitGroup
+}
+
+// NewDispatcher builds a Dispatcher over sinks. maxWorkers bounds the total
+// number of concurrent Sink.Send calls across all events; maxRetries is the
+// number of extra attempts made on a sink before giving up on that event.
+func NewDispatcher(sinks []Sink, maxWorkers, maxRetries int) *Dispatcher {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	return &Dispatcher{
+		sinks:      sinks,
+		maxRetries: maxRetries,
+		tokens:     make(chan struct{}, maxWorkers),
+	}
+}
+
+// Dispatch sends event to every sink concurrently and waits for all of them
+// to finish (or exhaust their retries). A failure on one sink never
+// prevents delivery to the others.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
+	d.inFlight.Add(1)
+	defer d.inFlight.Done()
+
+	var wg sync.WaitGroup
+	for _, s := range d.sinks {
+		wg.Add(1)
+		go func(s Sink) {
+			defer wg.Done()
+			d.tokens <- struct{}{}
+			defer func() { <-d.tokens }()
+			d.sendWithRetry(ctx, s, event)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// sendWithRetry calls s.Send, retrying with a short linear backoff up to
+// d.maxRetries times before logging a permanent failure for this sink.
+func (d *Dispatcher) sendWithRetry(ctx context.Context, s Sink, event Event) {
+	logger := loggerFromContext(ctx)
+	var err error
+	for attempt := 0; attempt <= d.maxRetries; attempt++ {
+		start := time.Now()
+		err = s.Send(ctx, event)
+		observeSinkSend(s.Name(), time.Since(start), err)
+		if err == nil {
+			return
+		}
+		if attempt == d.maxRetries {
+			break
+		}
+		logger.Warn().
+			Err(err).
+			Str("sink", s.Name()).
+			Int("attempt", attempt).
+			Msg("sink send failed, will retry")
+		time.Sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+	}
+	logger.Error().
+		Err(err).
+		Str("sink", s.Name()).
+		Msg("sink send failed permanently, dropping event")
+}
+
+// Drain waits for every in-flight Dispatch call to finish, up to ctx's
+// deadline. It lets shutdown wait for outstanding sink sends instead of
+// dropping them on the floor.
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes every sink in turn, collecting and returning the first error
+// encountered while still attempting to close the rest.
+func (d *Dispatcher) Close() error {
+	var firstErr error
+	for _, s := range d.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing sink %s: %w", s.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+// buildSinks constructs a Sink for each enabled entry in cfgs, skipping
+// disabled entries. An entry with an unknown type, or one whose sink fails
+// to build, makes it return an error.
+func buildSinks(cfgs []SinkConfig) ([]Sink, error) {
+	var sinks []Sink
+	for _, cfg := range cfgs {
+		if !cfg.Enabled {
+			continue
+		}
+		s, err := newSink(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("building sink %q: %w", cfg.Name, err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// newSink constructs the Sink implementation matching cfg.Type.
+func newSink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "nsca":
+		return newNscaSink(cfg)
+	case "sensu":
+		return newSensuSink(cfg)
+	case "webhook":
+		return newWebhookSink(cfg)
+	case "elasticsearch":
+		return newElasticsearchSink(cfg)
+	case "kafka":
+		return newKafkaSink(cfg)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// elasticsearchSinkOptions configures an elasticsearchSink.
+type elasticsearchSinkOptions struct {
+	URL            string `json:"url"`
+	Index          string `json:"index"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// elasticsearchSink indexes each event into Elasticsearch using the bulk
+// API. Events are not batched: every Send is its own single-document bulk
+// request.
+type elasticsearchSink struct {
+	name   string
+	url    string
+	index  string
+	client *http.Client
+}
+
+func newElasticsearchSink(cfg SinkConfig) (Sink, error) {
+	var opts elasticsearchSinkOptions
+	if err := decodeOptions(cfg.Options, &opts); err != nil {
+		return nil, fmt.Errorf("decoding options: %w", err)
+	}
+	if opts.URL == "" {
+		return nil, fmt.Errorf("elasticsearch sink %q: url is required", cfg.Name)
+	}
+	if opts.Index == "" {
+		opts.Index = "catchpoint-alerts"
+	}
+	if opts.TimeoutSeconds <= 0 {
+		opts.TimeoutSeconds = 5
+	}
+
+	return &elasticsearchSink{
+		name:   cfg.Name,
+		url:    opts.URL + "/_bulk",
+		index:  opts.Index,
+		client: &http.Client{Timeout: time.Duration(opts.TimeoutSeconds) * time.Second},
+	}, nil
+}
+
+func (s *elasticsearchSink) Name() string { return s.name }
+
+func (s *elasticsearchSink) Send(ctx context.Context, event Event) error {
+	action, err := json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": s.index},
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling bulk action: %w", err)
+	}
+
+	doc, err := json.Marshal(struct {
+		Criticality uint8     `json:"criticality"`
+		Service     string    `json:"service"`
+		Message     string    `json:"message"`
+		Timestamp   time.Time `json:"timestamp"`
+	}{event.Criticality, event.Service, event.Message, event.Timestamp})
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	body := append(action, '\n')
+	body = append(body, doc...)
+	body = append(body, '\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bulk indexing event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *elasticsearchSink) Close() error { return nil }
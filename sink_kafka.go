@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// kafkaSinkOptions configures a kafkaSink.
+type kafkaSinkOptions struct {
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+}
+
+// kafkaSink publishes each event as a JSON message on a Kafka topic.
+type kafkaSink struct {
+	name     string
+	topic    string
+	producer sarama.SyncProducer
+}
+
+func newKafkaSink(cfg SinkConfig) (Sink, error) {
+	var opts kafkaSinkOptions
+	if err := decodeOptions(cfg.Options, &opts); err != nil {
+		return nil, fmt.Errorf("decoding options: %w", err)
+	}
+	if len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka sink %q: brokers is required", cfg.Name)
+	}
+	if opts.Topic == "" {
+		return nil, fmt.Errorf("kafka sink %q: topic is required", cfg.Name)
+	}
+
+	saramaCfg := sarama.NewConfig()
+	saramaCfg.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer(opts.Brokers, saramaCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating kafka producer: %w", err)
+	}
+
+	return &kafkaSink{name: cfg.Name, topic: opts.Topic, producer: producer}, nil
+}
+
+func (s *kafkaSink) Name() string { return s.name }
+
+func (s *kafkaSink) Send(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: s.topic,
+		Value: sarama.ByteEncoder(payload),
+	})
+	return err
+}
+
+func (s *kafkaSink) Close() error {
+	return s.producer.Close()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// nscaSinkOptions configures a nscaSink.
+type nscaSinkOptions struct {
+	// Host and Port address the NSCA daemon, e.g. "127.0.0.1", 5667.
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Password is the shared secret configured in the daemon's nsca.cfg,
+	// if it uses XOR encryption. Leave empty to send unencrypted (beyond
+	// the mandatory per-connection IV XOR).
+	Password string `json:"password"`
+	// TimeoutSeconds bounds how long a single send is allowed to take.
+	// Defaults to 5.
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// nscaSink delivers events to a NSCA daemon as passive check results.
+type nscaSink struct {
+	name   string
+	client *nscaClient
+}
+
+func newNscaSink(cfg SinkConfig) (Sink, error) {
+	var opts nscaSinkOptions
+	if err := decodeOptions(cfg.Options, &opts); err != nil {
+		return nil, fmt.Errorf("decoding options: %w", err)
+	}
+	if opts.Host == "" {
+		return nil, fmt.Errorf("nsca sink %q: host is required", cfg.Name)
+	}
+	if opts.Port <= 0 {
+		opts.Port = 5667
+	}
+	if opts.TimeoutSeconds <= 0 {
+		opts.TimeoutSeconds = 5
+	}
+
+	return &nscaSink{
+		name: cfg.Name,
+		client: &nscaClient{
+			host:     opts.Host,
+			port:     opts.Port,
+			password: opts.Password,
+			timeout:  time.Duration(opts.TimeoutSeconds) * time.Second,
+		},
+	}, nil
+}
+
+func (s *nscaSink) Name() string { return s.name }
+
+func (s *nscaSink) Send(ctx context.Context, event Event) error {
+	if err := s.client.send(event.Criticality, event.Service, event.Message); err != nil {
+		return fmt.Errorf("sending nsca message: %w", err)
+	}
+	return nil
+}
+
+func (s *nscaSink) Close() error { return nil }
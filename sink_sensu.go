@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sensu is the wire format of a Sensu check result, shared by sensuSink
+// (POSTed to the Agent HTTP API) and the queue-backed sender endpoints that
+// a Sensu-style poller drains.
+type Sensu struct {
+	Name   string `json:"name"`
+	Status uint8  `json:"status"`
+	Output string `json:"output"`
+}
+
+// sensuSinkOptions configures a sensuSink.
+type sensuSinkOptions struct {
+	// URL is the base address of the Sensu Agent HTTP API, e.g.
+	// "http://127.0.0.1:3031".
+	URL string `json:"url"`
+	// TimeoutSeconds bounds how long a single POST is allowed to take, in
+	// seconds. Defaults to 5.
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// sensuSink posts each event straight to a Sensu Agent's HTTP API
+// (POST /events), replacing the old behaviour of stashing check results in
+// an in-memory cache for a poller to pick up later.
+type sensuSink struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func newSensuSink(cfg SinkConfig) (Sink, error) {
+	var opts sensuSinkOptions
+	if err := decodeOptions(cfg.Options, &opts); err != nil {
+		return nil, fmt.Errorf("decoding options: %w", err)
+	}
+	if opts.URL == "" {
+		return nil, fmt.Errorf("sensu sink %q: url is required", cfg.Name)
+	}
+	if opts.TimeoutSeconds <= 0 {
+		opts.TimeoutSeconds = 5
+	}
+
+	return &sensuSink{
+		name: cfg.Name,
+		url:  opts.URL + "/events",
+		client: &http.Client{
+			Timeout: time.Duration(opts.TimeoutSeconds) * time.Second,
+		},
+	}, nil
+}
+
+func (s *sensuSink) Name() string { return s.name }
+
+func (s *sensuSink) Send(ctx context.Context, event Event) error {
+	raw := Sensu{
+		Status: event.Criticality,
+		Name:   event.Service,
+		Output: event.Message,
+	}
+	body, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sensu agent responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *sensuSink) Close() error { return nil }